@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -27,6 +28,21 @@ func (w *levelFilterWriter) WriteLevel(level zerolog.Level, p []byte) (n int, er
 	return len(p), nil
 }
 
+// logCallerEnv is the environment variable controlling whether log records carry
+// the caller's file and line. Set it to "0" or "false" to skip the runtime.Caller
+// lookup on every record.
+const logCallerEnv = "MAAEND_LOG_CALLER"
+
+// isLogCallerEnabled reports whether caller info should be added to log records
+func isLogCallerEnabled() bool {
+	v, ok := os.LookupEnv(logCallerEnv)
+	if !ok {
+		return true
+	}
+	enabled, err := strconv.ParseBool(v)
+	return err != nil || enabled
+}
+
 func initLogger() (*os.File, error) {
 	debugDir := filepath.Join(".", "debug")
 	if err := os.MkdirAll(debugDir, 0755); err != nil {
@@ -51,11 +67,13 @@ func initLogger() (*os.File, error) {
 	// 文件输出所有级别的日志
 	multi := zerolog.MultiLevelWriter(consoleWriter, logFile)
 
-	log.Logger = zerolog.New(multi).
+	logCtx := zerolog.New(multi).
 		With().
-		Timestamp().
-		Caller().
-		Logger()
+		Timestamp()
+	if isLogCallerEnabled() {
+		logCtx = logCtx.Caller()
+	}
+	log.Logger = logCtx.Logger()
 
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 
@@ -0,0 +1,34 @@
+// Package maautil provides helpers shared by the custom components
+// working with MaaFramework results.
+package maautil
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseRecognitionDetail unmarshals a recognition detail into out, accepting
+// both the flat custom recognition detail and the one MaaFramework wraps in
+// "best.detail" when the recognition is run through a pipeline node
+func ParseRecognitionDetail(detailJson string, out any) error {
+	var wrapped struct {
+		Best *struct {
+			Detail json.RawMessage `json:"detail"`
+		} `json:"best"`
+	}
+	if err := json.Unmarshal([]byte(detailJson), &wrapped); err != nil {
+		return fmt.Errorf("failed to unmarshal recognition detail: %w", err)
+	}
+
+	data := []byte(detailJson)
+	if wrapped.Best != nil {
+		if len(wrapped.Best.Detail) == 0 {
+			return fmt.Errorf("recognition detail has no best.detail")
+		}
+		data = wrapped.Best.Detail
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal recognition detail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package maautil
+
+import "testing"
+
+type testDetail struct {
+	MapName string  `json:"mapName"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Rot     int     `json:"rot"`
+	LocConf float64 `json:"locConf"`
+	RotConf float64 `json:"rotConf"`
+}
+
+func TestParseRecognitionDetail(t *testing.T) {
+	want := testDetail{MapName: "map01_lv001", X: 120, Y: 340, Rot: 90, LocConf: 0.8, RotConf: 0.7}
+
+	tests := []struct {
+		name    string
+		detail  string
+		wantErr bool
+	}{
+		{
+			name:   "flat",
+			detail: `{"mapName":"map01_lv001","x":120,"y":340,"rot":90,"locConf":0.8,"rotConf":0.7}`,
+		},
+		{
+			name:   "wrapped",
+			detail: `{"all":[],"best":{"box":[0,0,1,1],"detail":{"mapName":"map01_lv001","x":120,"y":340,"rot":90,"locConf":0.8,"rotConf":0.7}},"filtered":[]}`,
+		},
+		{
+			name:    "wrapped without detail",
+			detail:  `{"best":{"box":[0,0,1,1]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "wrapped with invalid detail",
+			detail:  `{"best":{"detail":"not an object"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			detail:  `{"mapName":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testDetail
+			err := ParseRecognitionDetail(tt.detail, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
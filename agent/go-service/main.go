@@ -9,6 +9,11 @@ import (
 )
 
 func main() {
+	// Offline subcommands, no MAA framework or log file required
+	if len(os.Args) >= 2 && os.Args[1] == "validate-maps" {
+		os.Exit(runValidateMaps(os.Args[2:]))
+	}
+
 	logFile, err := initLogger()
 	if err != nil {
 		log.Fatal().
@@ -22,7 +27,7 @@ func main() {
 		Msg("MaaEnd Agent Service")
 
 	if len(os.Args) < 2 {
-		log.Fatal().Msg("Usage: go-service <identifier>")
+		log.Fatal().Msg("Usage: go-service <identifier> | go-service validate-maps <dir>")
 	}
 
 	identifier := os.Args[1]
@@ -22,12 +22,30 @@ var (
 	ROT_RADIUS   = 12
 )
 
+// Template matching configuration
+const (
+	MATCH_STEP_MIN     = 1    // Default minimum coarse search step of map matching
+	MATCH_STEP_MAX     = 3    // Default maximum coarse search step of map matching
+	MATCH_STEP_DIVISOR = 20.0 // Haystack size (square root of area) per coarse step unit
+	MATCH_ROT_STEP     = 3    // Coarse search step of pointer matching
+	MATCH_WORKERS      = 0    // Default number of coarse search workers, 0 for runtime.NumCPU()
+	SCALED_CACHE_SIZE  = 2    // Number of scaled map sets kept, one per precision in use
+)
+
+// Map validation configuration
+const (
+	VALIDATE_MIN_STDDEV = 2.0 // Minimum pixel standard deviation of a usable map
+)
+
 // Resource paths
 const (
 	MAP_DIR      = "image/MapTracker/map"
 	POINTER_PATH = "image/MapTracker/pointer.png"
 )
 
+// Supported map image extensions (lower case)
+var MAP_IMAGE_EXTS = []string{".png", ".jpg", ".jpeg", ".webp"}
+
 // Move action configuration
 const (
 	INFER_INTERVAL_MS                = 200
@@ -40,6 +58,10 @@ const (
 	FAILURE_ARRIVAL_MAX_DURATION_MS  = 60000
 	FAILURE_ROTATION_MAX_DURATION_MS = 30000
 	FAILURE_STUCK_MAX_DURATION_MS    = 10000
+	CAPTURE_TRIES                    = 3
+	CAPTURE_RETRY_DELAY_MS           = 50
+	PRINT_UI_TIMEOUT_MS              = 1000
+	MOVE_INFER_PRECISION             = 0.6
 )
 
 // Win32 action related codes
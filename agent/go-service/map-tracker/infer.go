@@ -6,16 +6,19 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
+	_ "image/jpeg"
 	_ "image/png"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
+	_ "golang.org/x/image/webp"
 )
 
 // InferResult represents the result of map tracking inference
@@ -28,13 +31,21 @@ type InferResult struct {
 	RotConf   float64 `json:"rotConf"`   // Rotation confidence
 	LocTimeMs int64   `json:"locTimeMs"` // Location inference time in ms
 	RotTimeMs int64   `json:"rotTimeMs"` // Rotation inference time in ms
+
+	locTime, rotTime time.Duration // Unrounded inference times for logging
 }
 
 // InferParam represents the parameters for map tracking inference
 type InferParam struct {
-	MapNameRegex string  `json:"map_name_regex"` // Regex to filter map names
-	Precision    float64 `json:"precision"`      // Matching precision (0.0, 1.0]
-	Threshold    float64 `json:"threshold"`      // Confidence threshold [0.0, 1.0)
+	MapNameRegex        string  `json:"map_name_regex"`       // Regex to filter map names
+	Precision           float64 `json:"precision"`            // Matching precision (0.0, 1.0]
+	Threshold           float64 `json:"threshold"`            // Confidence threshold [0.0, 1.0)
+	StationaryThreshold float64 `json:"stationary_threshold"` // Mini-map difference to reuse last location, 0 to disable
+	Reset               bool    `json:"reset"`                // Forget the last location before inference
+	Gamma               float64 `json:"gamma"`                // Gamma applied to maps and mini-map, 1 to disable
+	CoarseStepMin       int     `json:"coarse_step_min"`      // Minimum coarse search step of map matching
+	CoarseStepMax       int     `json:"coarse_step_max"`      // Maximum coarse search step of map matching
+	MatchWorkers        int     `json:"match_workers"`        // Number of coarse search workers, 0 for runtime.NumCPU()
 }
 
 // MapData represents a preloaded map image
@@ -46,90 +57,196 @@ type MapData struct {
 	OffsetY  int
 }
 
-// Infer is the custom recognition component for map tracking
+// scaledMap represents a map scaled for matching and its coarse search step
+type scaledMap struct {
+	MapData
+	Step int
+}
+
+// scaledKey identifies a set of scaled maps by the parameters they were built with
+type scaledKey struct {
+	Scale, Gamma     float64
+	StepMin, StepMax int
+}
+
+// scaledEntry is a set of scaled maps cached under its key
+type scaledEntry struct {
+	Key  scaledKey
+	Maps []scaledMap
+}
+
+// Infer is the custom recognition component for map tracking.
+// It is safe for concurrent use: resources are loaded once (a failed load is
+// retried by the next call), cached data is guarded by mutexes and replaced
+// rather than mutated, and all matching buffers are local to each call.
 type Infer struct {
-	// Cache for preloaded resources
-	mapsOnce    sync.Once
-	pointerOnce sync.Once
-	maps        []MapData
-	pointer     *image.RGBA
-	mapsErr     error
-	pointerErr  error
-
-	// Cache for scaled maps
+	// Cache for preloaded resources, set once loaded successfully
+	mapsMu    sync.Mutex
+	pointerMu sync.Mutex
+	maps      []MapData
+	pointer   *image.RGBA
+
+	// Cache for scaled maps, most recently used first
 	scaledMu    sync.Mutex
-	scaledScale float64
-	scaledMaps  []MapData
+	scaledCache []scaledEntry
+
+	// Cache for the last location inference
+	lastLocMu sync.Mutex
+	lastLoc   *locationCache
+}
+
+// locationCache holds the last location inference and the mini-map it was made from
+type locationCache struct {
+	MiniMap *image.RGBA
+	Scale   float64
+	Gamma   float64
+	Regex   string
+	X, Y    int
+	Conf    float64
+	MapName string
 }
 
 var _ maa.CustomRecognitionRunner = &Infer{}
 
+var warmupOnce sync.Once
+
 // Run implements maa.CustomRecognitionRunner
 func (i *Infer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (*maa.CustomRecognitionResult, bool) {
 	// Parse custom recognition parameters
-	precision := 0.4
-	threshold := 0.5
-	mapNameRegexStr := "^map\\d+_lv\\d+$"
+	param := DefaultInferParam()
 	if arg.CustomRecognitionParam != "" {
 		var params InferParam
 		if err := json.Unmarshal([]byte(arg.CustomRecognitionParam), &params); err == nil {
 			if params.MapNameRegex != "" {
-				mapNameRegexStr = params.MapNameRegex
+				param.MapNameRegex = params.MapNameRegex
 			}
 			if params.Precision > 0.0 && params.Precision <= 1.0 {
-				precision = params.Precision
+				param.Precision = params.Precision
 			}
 			if params.Threshold >= 0.0 && params.Threshold < 1.0 {
-				threshold = params.Threshold
+				param.Threshold = params.Threshold
+			}
+			if params.StationaryThreshold > 0.0 {
+				param.StationaryThreshold = params.StationaryThreshold
+			}
+			param.Reset = params.Reset
+			if params.Gamma > 0.0 {
+				param.Gamma = params.Gamma
+			}
+			if params.CoarseStepMin > 0 {
+				param.CoarseStepMin = params.CoarseStepMin
+			}
+			if params.CoarseStepMax > 0 {
+				param.CoarseStepMax = params.CoarseStepMax
+			}
+			if params.MatchWorkers > 0 {
+				param.MatchWorkers = params.MatchWorkers
 			}
 		}
 	}
 
-	// Compile regex
-	mapNameRegex, err := regexp.Compile(mapNameRegexStr)
+	result, err := i.InferImage(arg.Img, param)
+	if err != nil {
+		log.Error().Err(err).Msg("Map tracking inference failed")
+		return nil, false
+	}
+
+	// Determine if recognition hit
+	hit := result.LocConf > param.Threshold && result.RotConf > param.Threshold
+
+	// Serialize result to JSON
+	detailJSON, err := json.Marshal(result)
 	if err != nil {
-		log.Error().Err(err).Str("regex", mapNameRegexStr).Msg("Invalid map_name_regex")
+		log.Error().Err(err).Msg("Failed to marshal result")
 		return nil, false
 	}
 
-	locScale := precision
+	log.Info().
+		Str("mapName", result.MapName).
+		Int("x", result.X).
+		Int("y", result.Y).
+		Int("rot", result.Rot).
+		Dur("locTime", result.locTime).
+		Dur("rotTime", result.rotTime).
+		Float64("locConf", result.LocConf).
+		Float64("rotConf", result.RotConf).
+		Bool("hit", hit).
+		Msg("Map tracking inference completed")
+
+	return &maa.CustomRecognitionResult{
+		Box:    arg.Roi,
+		Detail: string(detailJSON),
+	}, hit
+}
+
+// DefaultInferParam returns the default parameters for map tracking inference
+func DefaultInferParam() InferParam {
+	return InferParam{
+		MapNameRegex:  "^map\\d+_lv\\d+$",
+		Precision:     0.4,
+		Threshold:     0.5,
+		Gamma:         1.0,
+		CoarseStepMin: MATCH_STEP_MIN,
+		CoarseStepMax: MATCH_STEP_MAX,
+		MatchWorkers:  MATCH_WORKERS,
+	}
+}
+
+// InferImage infers the player's location and rotation from a screen image.
+// It does not depend on a MAA context, so it can also be used offline.
+// The threshold in param is not applied; callers decide whether the result is a hit.
+func (i *Infer) InferImage(screenImg image.Image, param InferParam) (*InferResult, error) {
+	if param.Precision <= 0.0 || param.Precision > 1.0 {
+		return nil, fmt.Errorf("precision %v out of range (0.0, 1.0]", param.Precision)
+	}
+	if param.Gamma <= 0.0 {
+		return nil, fmt.Errorf("gamma %v must be positive", param.Gamma)
+	}
+	if param.CoarseStepMin < 1 || param.CoarseStepMax < param.CoarseStepMin {
+		return nil, fmt.Errorf("coarse step range [%d, %d] is invalid", param.CoarseStepMin, param.CoarseStepMax)
+	}
+	if param.MatchWorkers < 0 {
+		return nil, fmt.Errorf("match_workers %d must not be negative", param.MatchWorkers)
+	}
+
+	// Compile regex
+	mapNameRegex, err := regexp.Compile(param.MapNameRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid map_name_regex %q: %w", param.MapNameRegex, err)
+	}
+
+	if param.Reset {
+		i.Reset()
+	}
+
 	var rotStep int
-	if precision < 0.3 {
+	if param.Precision < 0.3 {
 		rotStep = 12
-	} else if precision < 0.6 {
+	} else if param.Precision < 0.6 {
 		rotStep = 6
 	} else {
 		rotStep = 3
 	}
 
 	// Initialize resources on first run
-	i.initMaps(ctx)
-	i.initPointer(ctx)
-
-	// Check for initialization errors
-	if i.mapsErr != nil {
-		log.Error().Err(i.mapsErr).Msg("Failed to initialize maps")
-		return nil, false
+	if err := i.initMaps(); err != nil {
+		return nil, fmt.Errorf("failed to initialize maps: %w", err)
 	}
-	if i.pointerErr != nil {
-		log.Error().Err(i.pointerErr).Msg("Failed to initialize pointer")
-		return nil, false
+	if err := i.initPointer(); err != nil {
+		return nil, fmt.Errorf("failed to initialize pointer: %w", err)
 	}
 
 	// Perform location inference
 	t0 := time.Now()
-	locX, locY, locConf, mapName := i.inferLocation(arg.Img, locScale, mapNameRegex)
+	locX, locY, locConf, mapName := i.inferLocation(screenImg, param, mapNameRegex)
 	locTime := time.Since(t0)
 
-	// Perform rotation inference (if pointer is loaded)
-	rot, rotConf := 0, 0.0
-	var rotTime time.Duration
+	// Perform rotation inference
 	t1 := time.Now()
-	rot, rotConf = i.inferRotation(arg.Img, rotStep)
-	rotTime = time.Since(t1)
+	rot, rotConf := i.inferRotation(screenImg, rotStep, param.MatchWorkers)
+	rotTime := time.Since(t1)
 
-	// Build result
-	result := InferResult{
+	return &InferResult{
 		MapName:   mapName,
 		X:         locX,
 		Y:         locY,
@@ -138,70 +255,153 @@ func (i *Infer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (*maa.Custo
 		RotConf:   rotConf,
 		LocTimeMs: locTime.Milliseconds(),
 		RotTimeMs: rotTime.Milliseconds(),
-	}
+		locTime:   locTime,
+		rotTime:   rotTime,
+	}, nil
+}
 
-	// Determine if recognition hit
-	hit := locConf > threshold && rotConf > threshold
+// Warmup loads the map images and pointer template of the registered
+// MapTrackerInfer in the background, and scales the maps for the default
+// precisions of MapTrackerInfer and MapTrackerMove, so the first inference
+// does not stall. Other precisions, gammas or coarse step ranges are still
+// scaled on their first use.
+// It is safe to call at any time and more than once; a concurrent inference
+// waits for the same load, and a failed load is retried by the next inference.
+func Warmup() {
+	warmupOnce.Do(func() {
+		go func() {
+			t0 := time.Now()
+			if inferInstance.warmup() != nil {
+				return
+			}
+			log.Info().Dur("duration", time.Since(t0)).Msg("Map tracker warmed up")
+		}()
+	})
+}
 
-	// Serialize result to JSON
-	detailJSON, err := json.Marshal(result)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to marshal result")
-		return nil, false
+// warmup loads the resources and fills the scaled maps cache for the default precisions
+func (i *Infer) warmup() error {
+	if err := i.initMaps(); err != nil {
+		return err
 	}
+	if err := i.initPointer(); err != nil {
+		return err
+	}
+	param := DefaultInferParam()
+	for _, precision := range []float64{MOVE_INFER_PRECISION, param.Precision} {
+		i.getScaledMaps(scaledKey{precision, param.Gamma, param.CoarseStepMin, param.CoarseStepMax})
+	}
+	return nil
+}
 
-	log.Info().
-		Str("mapName", mapName).
-		Int("x", locX).
-		Int("y", locY).
-		Int("rot", rot).
-		Dur("locTime", locTime).
-		Dur("rotTime", rotTime).
-		Float64("locConf", locConf).
-		Float64("rotConf", rotConf).
-		Bool("hit", hit).
-		Msg("Map tracking inference completed")
+// initMaps initializes the map cache (thread-safe, retried until it succeeds)
+func (i *Infer) initMaps() error {
+	i.mapsMu.Lock()
+	defer i.mapsMu.Unlock()
+	if i.maps != nil {
+		return nil
+	}
 
-	return &maa.CustomRecognitionResult{
-		Box:    arg.Roi,
-		Detail: string(detailJSON),
-	}, hit
+	maps, err := i.loadMaps()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load maps")
+		return err
+	}
+	i.maps = maps
+	log.Info().Int("mapsCount", len(i.maps)).Msg("Map images loaded")
+	return nil
 }
 
-// initMaps initializes the map cache (thread-safe, runs once)
-func (i *Infer) initMaps(ctx *maa.Context) {
-	i.mapsOnce.Do(func() {
-		i.maps, i.mapsErr = i.loadMaps(ctx)
-		if i.mapsErr != nil {
-			log.Error().Err(i.mapsErr).Msg("Failed to load maps")
-		} else {
-			log.Info().Int("mapsCount", len(i.maps)).Msg("Map images loaded")
-		}
-	})
-}
+// initPointer initializes the pointer template cache (thread-safe, retried until it succeeds)
+func (i *Infer) initPointer() error {
+	i.pointerMu.Lock()
+	defer i.pointerMu.Unlock()
+	if i.pointer != nil {
+		return nil
+	}
 
-// initPointer initializes the pointer template cache (thread-safe, runs once)
-func (i *Infer) initPointer(ctx *maa.Context) {
-	i.pointerOnce.Do(func() {
-		i.pointer, i.pointerErr = i.loadPointer(ctx)
-		if i.pointerErr != nil {
-			log.Error().Err(i.pointerErr).Msg("Failed to load pointer template")
-		} else {
-			log.Info().Msg("Pointer template image loaded")
-		}
-	})
+	pointer, err := i.loadPointer()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load pointer template")
+		return err
+	}
+	i.pointer = pointer
+	log.Info().Msg("Pointer template image loaded")
+	return nil
 }
 
 // loadMaps loads all map images from the resource directory
 // and try crops them if map_rect.json exists
-func (i *Infer) loadMaps(ctx *maa.Context) ([]MapData, error) {
+func (i *Infer) loadMaps() ([]MapData, error) {
 	// Find map directory using search strategy
 	mapDir := findResource(MAP_DIR)
 	if mapDir == "" {
 		return nil, fmt.Errorf("map directory not found (searched in cache and standard locations)")
 	}
 
-	// Read map_rect.json if it exists
+	rectList := readMapRects(mapDir)
+	filenames, err := listMapFiles(mapDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip files sharing a map name with an earlier one (e.g. "x_merged.png" and "x.webp")
+	seen := make(map[string]string, len(filenames))
+	unique := filenames[:0]
+	for _, filename := range filenames {
+		name := getMapName(filename)
+		if prev, ok := seen[name]; ok {
+			log.Warn().Str("file", filename).Str("name", name).Str("previous", prev).Msg("Duplicate map name, skipped")
+			continue
+		}
+		seen[name] = filename
+		unique = append(unique, filename)
+	}
+	filenames = unique
+
+	// Load files concurrently with a bounded worker pool
+	type loadResult struct {
+		data *MapData
+		err  error
+	}
+	results := make([]loadResult, len(filenames))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), len(filenames)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				data, err := loadMapFile(mapDir, filenames[idx], rectList)
+				results[idx] = loadResult{data, err}
+			}
+		}()
+	}
+	for idx := range filenames {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Merge results in directory order
+	maps := make([]MapData, 0, len(filenames))
+	for idx, res := range results {
+		if res.err != nil {
+			log.Warn().Err(res.err).Str("file", filenames[idx]).Msg("Failed to load map image")
+			continue
+		}
+		maps = append(maps, *res.data)
+	}
+
+	if len(maps) == 0 {
+		return nil, fmt.Errorf("no valid map images found in %s", mapDir)
+	}
+
+	return maps, nil
+}
+
+// readMapRects reads map_rect.json in the map directory if it exists
+func readMapRects(mapDir string) map[string][]int {
 	rectList := make(map[string][]int)
 	rectPath := filepath.Join(mapDir, "map_rect.json")
 	if data, err := os.ReadFile(rectPath); err == nil {
@@ -211,81 +411,103 @@ func (i *Infer) loadMaps(ctx *maa.Context) ([]MapData, error) {
 			log.Info().Msg("Map rect JSON loaded")
 		}
 	}
+	return rectList
+}
 
-	// Read directory entries
+// listMapFiles returns the names of all map image files in the map directory
+func listMapFiles(mapDir string) ([]string, error) {
 	entries, err := os.ReadDir(mapDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read map directory: %w", err)
 	}
 
-	// Load all PNG files
-	maps := make([]MapData, 0)
+	filenames := make([]string, 0, len(entries))
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 
 		filename := entry.Name()
-		if !strings.HasSuffix(filename, ".png") {
+		if !isMapImageFile(filename) {
 			continue
 		}
+		filenames = append(filenames, filename)
+	}
+	return filenames, nil
+}
 
-		// Load image
-		imgPath := filepath.Join(mapDir, filename)
-		file, err := os.Open(imgPath)
-		if err != nil {
-			log.Warn().Err(err).Str("path", imgPath).Msg("Failed to open map image")
-			continue
+// isMapImageFile reports whether the file has a supported map image extension
+func isMapImageFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range MAP_IMAGE_EXTS {
+		if ext == e {
+			return true
 		}
+	}
+	return false
+}
 
-		img, _, err := image.Decode(file)
-		file.Close()
-		if err != nil {
-			log.Warn().Err(err).Str("path", imgPath).Msg("Failed to decode map image")
-			continue
-		}
+// getMapName extracts the map name from a map image file name
+// (removes extension and "_merged" suffix)
+func getMapName(filename string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filename, filepath.Ext(filename)), "_merged")
+}
 
-		// Extract map name (remove "_merged.png" suffix)
-		name := strings.TrimSuffix(filename, "_merged.png")
-
-		var imgRGBA *image.RGBA
-		offsetX, offsetY := 0, 0
-
-		// Crop if valid rect exists
-		if r, ok := rectList[name]; ok && len(r) == 4 {
-			rect := image.Rect(r[0], r[1], r[2], r[3])
-			// Crop precisely using drawing
-			b := img.Bounds()
-			r0 := rect.Intersect(b)
-			dst := image.NewRGBA(image.Rect(0, 0, r0.Dx(), r0.Dy()))
-			draw.Draw(dst, dst.Bounds(), img, r0.Min, draw.Src)
-			imgRGBA = dst
-			offsetX, offsetY = r0.Min.X, r0.Min.Y
-		} else {
-			imgRGBA = ToRGBA(img)
-		}
+// loadMapFile loads a single map image and try crops it by the given rect list
+func loadMapFile(mapDir, filename string, rectList map[string][]int) (*MapData, error) {
+	name := getMapName(filename)
+
+	var rect *image.Rectangle
+	if r, ok := rectList[name]; ok && len(r) == 4 {
+		r0 := image.Rect(r[0], r[1], r[2], r[3])
+		rect = &r0
+	}
+	imgRGBA, offsetX, offsetY, err := decodeMapImage(filepath.Join(mapDir, filename), rect)
+	if err != nil {
+		return nil, err
+	}
 
-		// Precompute integral image
-		integral := NewIntegralImage(imgRGBA)
+	// Precompute integral image
+	integral := NewIntegralImage(imgRGBA)
 
-		maps = append(maps, MapData{
-			Name:     name,
-			Img:      imgRGBA,
-			Integral: integral,
-			OffsetX:  offsetX,
-			OffsetY:  offsetY,
-		})
+	return &MapData{
+		Name:     name,
+		Img:      imgRGBA,
+		Integral: integral,
+		OffsetX:  offsetX,
+		OffsetY:  offsetY,
+	}, nil
+}
+
+// decodeMapImage decodes a map image and crops it by rect if given
+// Returns (image, offsetX, offsetY, error)
+func decodeMapImage(imgPath string, rect *image.Rectangle) (*image.RGBA, int, int, error) {
+	// Load image
+	file, err := os.Open(imgPath)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to open map image: %w", err)
 	}
 
-	if len(maps) == 0 {
-		return nil, fmt.Errorf("no valid map images found in %s", mapDir)
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode map image: %w", err)
 	}
 
-	return maps, nil
+	if rect == nil {
+		return ToRGBA(img), 0, 0, nil
+	}
+
+	// Crop precisely using drawing
+	b := img.Bounds()
+	r0 := rect.Intersect(b)
+	dst := image.NewRGBA(image.Rect(0, 0, r0.Dx(), r0.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r0.Min, draw.Src)
+	return dst, r0.Min.X, r0.Min.Y, nil
 }
 
 // loadPointer loads the pointer template image
-func (i *Infer) loadPointer(ctx *maa.Context) (*image.RGBA, error) {
+func (i *Infer) loadPointer() (*image.RGBA, error) {
 	// Find pointer template using search strategy
 	pointerPath := findResource(POINTER_PATH)
 	if pointerPath == "" {
@@ -310,9 +532,11 @@ func (i *Infer) loadPointer(ctx *maa.Context) (*image.RGBA, error) {
 
 // inferLocation infers the player's location on the map
 // Returns (x, y, confidence, mapName)
-func (i *Infer) inferLocation(screenImg image.Image, locScale float64, mapNameRegex *regexp.Regexp) (int, int, float64, string) {
+func (i *Infer) inferLocation(screenImg image.Image, param InferParam, mapNameRegex *regexp.Regexp) (int, int, float64, string) {
+	locScale, gamma, stationaryThreshold := param.Precision, param.Gamma, param.StationaryThreshold
+
 	// Use cached scaled maps
-	scaledMaps := i.getScaledMaps(locScale)
+	scaledMaps := i.getScaledMaps(scaledKey{locScale, gamma, param.CoarseStepMin, param.CoarseStepMax})
 	if len(scaledMaps) == 0 {
 		log.Warn().Msg("No maps available for matching")
 		return 0, 0, 0.0, "None"
@@ -327,6 +551,21 @@ func (i *Infer) inferLocation(screenImg image.Image, locScale float64, mapNameRe
 	}
 
 	miniMapRGBA := ToRGBA(miniMap)
+	if gamma != 1.0 {
+		// Copy first, the mini-map may share pixels with the screen image
+		miniMapRGBA = CloneRGBA(miniMapRGBA)
+		ApplyGamma(miniMapRGBA, gamma)
+	}
+
+	// Reuse the last location if the mini-map has barely changed
+	if stationaryThreshold > 0.0 {
+		if last := i.getLastLocation(locScale, gamma, mapNameRegex.String()); last != nil {
+			if diff := MeanAbsDiff(last.MiniMap, miniMapRGBA); diff < stationaryThreshold {
+				log.Debug().Float64("diff", diff).Msg("Mini-map unchanged, reusing last location")
+				return last.X, last.Y, last.Conf, last.MapName
+			}
+		}
+	}
 
 	miniMapBounds := miniMap.Bounds()
 	miniMapW, miniMapH := miniMapBounds.Dx(), miniMapBounds.Dy()
@@ -353,7 +592,7 @@ func (i *Infer) inferLocation(screenImg image.Image, locScale float64, mapNameRe
 
 		// Perform template matching (using optimized version with precomputed stats)
 		// Note: mapData.Img is already cropped if a rect was provided in map_rect.json
-		matchX, matchY, matchVal := MatchTemplateOptimized(mapData.Img, mapData.Integral, miniMapRGBA, miniStats)
+		matchX, matchY, matchVal := MatchTemplateOptimized(mapData.Img, mapData.Integral, miniMapRGBA, miniStats, mapData.Step, param.MatchWorkers)
 
 		if matchVal > bestVal {
 			bestVal = matchVal
@@ -369,6 +608,19 @@ func (i *Infer) inferLocation(screenImg image.Image, locScale float64, mapNameRe
 		log.Warn().Str("regex", mapNameRegex.String()).Msg("No maps matched the regex")
 	}
 
+	if stationaryThreshold > 0.0 {
+		i.setLastLocation(&locationCache{
+			MiniMap: CloneRGBA(miniMapRGBA),
+			Scale:   locScale,
+			Gamma:   gamma,
+			Regex:   mapNameRegex.String(),
+			X:       bestX,
+			Y:       bestY,
+			Conf:    bestVal,
+			MapName: bestMapName,
+		})
+	}
+
 	log.Debug().Int("triedMaps", triedCount).
 		Float64("bestVal", bestVal).
 		Str("bestMap", bestMapName).
@@ -377,36 +629,86 @@ func (i *Infer) inferLocation(screenImg image.Image, locScale float64, mapNameRe
 	return bestX, bestY, bestVal, bestMapName
 }
 
-// getScaledMaps returns cached scaled maps or recomputes them
-func (i *Infer) getScaledMaps(scale float64) []MapData {
+// Reset forgets the last location, so the next inference always runs a full match
+func (i *Infer) Reset() {
+	i.setLastLocation(nil)
+	log.Debug().Msg("Map tracking state reset")
+}
+
+// getLastLocation returns the last location inference made with the same scale, gamma and regex
+func (i *Infer) getLastLocation(scale, gamma float64, regex string) *locationCache {
+	i.lastLocMu.Lock()
+	defer i.lastLocMu.Unlock()
+
+	if i.lastLoc == nil || i.lastLoc.Scale != scale || i.lastLoc.Gamma != gamma || i.lastLoc.Regex != regex {
+		return nil
+	}
+	return i.lastLoc
+}
+
+// setLastLocation stores the last location inference
+func (i *Infer) setLastLocation(last *locationCache) {
+	i.lastLocMu.Lock()
+	defer i.lastLocMu.Unlock()
+
+	i.lastLoc = last
+}
+
+// getScaledMaps returns cached scaled maps or recomputes them,
+// keeping up to SCALED_CACHE_SIZE sets of the most recently used keys
+func (i *Infer) getScaledMaps(key scaledKey) []scaledMap {
 	i.scaledMu.Lock()
 	defer i.scaledMu.Unlock()
 
-	if i.scaledScale == scale && len(i.scaledMaps) > 0 {
-		return i.scaledMaps
+	for n, entry := range i.scaledCache {
+		if entry.Key == key {
+			// Move to front, replacing the slice rather than mutating it
+			cache := make([]scaledEntry, 0, len(i.scaledCache))
+			cache = append(cache, entry)
+			cache = append(cache, i.scaledCache[:n]...)
+			i.scaledCache = append(cache, i.scaledCache[n+1:]...)
+			return entry.Maps
+		}
 	}
 
-	log.Info().Float64("scale", scale).Msg("Recomputing scaled maps cache")
-	newScaled := make([]MapData, 0, len(i.maps))
+	log.Info().Float64("scale", key.Scale).Float64("gamma", key.Gamma).Msg("Recomputing scaled maps cache")
+	newScaled := make([]scaledMap, 0, len(i.maps))
 	for _, m := range i.maps {
-		sImg := scaleImage(m.Img, scale)
+		sImg := scaleImage(m.Img, key.Scale)
 		sRGBA := ToRGBA(sImg)
-		newScaled = append(newScaled, MapData{
-			Name:     m.Name,
-			Img:      sRGBA,
-			Integral: NewIntegralImage(sRGBA),
-			OffsetX:  m.OffsetX,
-			OffsetY:  m.OffsetY,
+		if key.Gamma != 1.0 {
+			// Copy first if unscaled, the loaded map must stay untouched
+			if sRGBA == m.Img {
+				sRGBA = CloneRGBA(sRGBA)
+			}
+			ApplyGamma(sRGBA, key.Gamma)
+		}
+		step := GetCoarseStep(sRGBA.Rect.Dx(), sRGBA.Rect.Dy(), key.StepMin, key.StepMax)
+		log.Debug().
+			Str("map", m.Name).
+			Int("step", step).
+			Msg("Coarse step chosen for scaled map")
+		newScaled = append(newScaled, scaledMap{
+			MapData: MapData{
+				Name:     m.Name,
+				Img:      sRGBA,
+				Integral: NewIntegralImage(sRGBA),
+				OffsetX:  m.OffsetX,
+				OffsetY:  m.OffsetY,
+			},
+			Step: step,
 		})
 	}
-	i.scaledScale = scale
-	i.scaledMaps = newScaled
-	return i.scaledMaps
+	cache := make([]scaledEntry, 0, SCALED_CACHE_SIZE)
+	cache = append(cache, scaledEntry{Key: key, Maps: newScaled})
+	cache = append(cache, i.scaledCache[:min(len(i.scaledCache), SCALED_CACHE_SIZE-1)]...)
+	i.scaledCache = cache
+	return newScaled
 }
 
 // inferRotation infers the player's rotation angle
 // Returns (angle, confidence)
-func (i *Infer) inferRotation(screenImg image.Image, rotStep int) (int, float64) {
+func (i *Infer) inferRotation(screenImg image.Image, rotStep, workers int) (int, float64) {
 	if i.pointer == nil {
 		return 0, 0.0
 	}
@@ -431,7 +733,7 @@ func (i *Infer) inferRotation(screenImg image.Image, rotStep int) (int, float64)
 
 		// Match against pointer template
 		integral := NewIntegralImage(rotatedRGBA)
-		_, _, matchVal := MatchTemplateOptimized(rotatedRGBA, integral, i.pointer, pointerStats)
+		_, _, matchVal := MatchTemplateOptimized(rotatedRGBA, integral, i.pointer, pointerStats, MATCH_ROT_STEP, workers)
 
 		if matchVal > maxVal {
 			maxVal = matchVal
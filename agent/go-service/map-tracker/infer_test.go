@@ -0,0 +1,452 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testWebP is a 1x1 transparent lossless WebP; Go has no WebP encoder
+var testWebP = []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+// writeTestImage encodes img to path in the format given by its extension
+func writeTestImage(t testing.TB, path string, img image.Image) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		err = png.Encode(file, img)
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(file, img, &jpeg.Options{Quality: 95})
+	case ".webp":
+		_, err = file.Write(testWebP)
+	default:
+		t.Fatalf("unsupported test image format: %s", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setupTestResource points the resource lookup at a fresh temporary directory
+// and returns the map directory to populate
+func setupTestResource(t testing.TB) string {
+	t.Helper()
+	base := t.TempDir()
+	mapDir := filepath.Join(base, MAP_DIR)
+	if err := os.MkdirAll(mapDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := getResourceBase()
+	resourcePath.Store(base)
+	t.Cleanup(func() { resourcePath.Store(prev) })
+	return mapDir
+}
+
+func TestLoadMapFileFormats(t *testing.T) {
+	mapDir := setupTestResource(t)
+	src := newTestMap(48, 32, 5)
+
+	tests := []struct {
+		filename string
+		name     string
+		w, h     int
+	}{
+		{"a_merged.png", "a", 48, 32},
+		{"b.jpg", "b", 48, 32},
+		{"c.JPEG", "c", 48, 32},
+		{"d_merged.webp", "d", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			writeTestImage(t, filepath.Join(mapDir, tt.filename), src)
+
+			data, err := loadMapFile(mapDir, tt.filename, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Name != tt.name {
+				t.Errorf("name %q, want %q", data.Name, tt.name)
+			}
+			if w, h := data.Img.Rect.Dx(), data.Img.Rect.Dy(); w != tt.w || h != tt.h {
+				t.Errorf("size %dx%d, want %dx%d", w, h, tt.w, tt.h)
+			}
+		})
+	}
+}
+
+func TestLoadMapFilePixels(t *testing.T) {
+	mapDir := setupTestResource(t)
+	src := newTestMap(40, 30, 6)
+	writeTestImage(t, filepath.Join(mapDir, "m.png"), src)
+
+	rects := map[string][]int{"m": {5, 4, 25, 24}}
+	data, err := loadMapFile(mapDir, "m.png", rects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.OffsetX != 5 || data.OffsetY != 4 {
+		t.Errorf("offset (%d, %d), want (5, 4)", data.OffsetX, data.OffsetY)
+	}
+	want := cropTestNeedle(src, 5, 4, 20, 20)
+	if MeanAbsDiff(data.Img, want) != 0 {
+		t.Error("cropped pixels differ from source")
+	}
+}
+
+func TestLoadMapsSkipsDuplicateNames(t *testing.T) {
+	mapDir := setupTestResource(t)
+	writeTestImage(t, filepath.Join(mapDir, "x_merged.png"), newTestMap(40, 30, 7))
+	writeTestImage(t, filepath.Join(mapDir, "x.webp"), nil)
+	writeTestImage(t, filepath.Join(mapDir, "y.png"), newTestMap(40, 30, 8))
+
+	maps, err := (&Infer{}).loadMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 {
+		t.Fatalf("loaded %d maps, want 2", len(maps))
+	}
+	for _, m := range maps {
+		// Directory order puts "x.webp" before "x_merged.png"
+		if m.Name == "x" && m.Img.Rect.Dx() != 1 {
+			t.Errorf("map x loaded from the later file, want x.webp")
+		}
+	}
+}
+
+// newTestScreen renders a screenshot whose mini-map shows src centered at (x, y)
+func newTestScreen(src *image.RGBA, x, y int) *image.RGBA {
+	screen := image.NewRGBA(image.Rect(0, 0, WORK_W, WORK_H))
+	size := 2*LOC_RADIUS + 1
+	r := image.Rect(LOC_CENTER_X-LOC_RADIUS, LOC_CENTER_Y-LOC_RADIUS, LOC_CENTER_X-LOC_RADIUS+size, LOC_CENTER_Y-LOC_RADIUS+size)
+	draw.Draw(screen, r, src, image.Point{x - LOC_RADIUS, y - LOC_RADIUS}, draw.Src)
+	return screen
+}
+
+// setupTestMaps writes a set of synthetic maps and a pointer template into a
+// fresh test resource directory, returning the source images by map name
+func setupTestMaps(t testing.TB) map[string]*image.RGBA {
+	t.Helper()
+	mapDir := setupTestResource(t)
+	maps := map[string]*image.RGBA{
+		"map01_lv001":  newTestMap(400, 300, 11),
+		"map01_lv002":  newTestMap(360, 320, 12),
+		"map02_lv001":  newTestMap(300, 260, 13),
+		"map99_sample": newTestMap(200, 200, 14),
+	}
+	for name, img := range maps {
+		writeTestImage(t, filepath.Join(mapDir, name+".png"), img)
+	}
+	writeTestImage(t, filepath.Join(filepath.Dir(mapDir), filepath.Base(POINTER_PATH)), newTestMap(15, 20, 15))
+	return maps
+}
+
+func TestInferImageConcurrent(t *testing.T) {
+	maps := setupTestMaps(t)
+	infer := &Infer{}
+
+	type testCase struct {
+		mapName string
+		x, y    int
+		param   InferParam
+	}
+	cases := []testCase{}
+	for n, precision := range []float64{0.4, 0.6} {
+		for _, name := range []string{"map01_lv001", "map01_lv002", "map02_lv001"} {
+			param := DefaultInferParam()
+			param.Precision = precision
+			param.StationaryThreshold = float64(n) // Also exercise the last location cache
+			cases = append(cases, testCase{name, 120 + 20*n, 110 + 15*n, param})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 2; round++ {
+		for _, tc := range cases {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := infer.InferImage(newTestScreen(maps[tc.mapName], tc.x, tc.y), tc.param)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				tol := int(2/tc.param.Precision) + 1
+				if res.MapName != tc.mapName || abs(res.X-tc.x) > tol || abs(res.Y-tc.y) > tol {
+					t.Errorf("precision %.1f: got %s (%d, %d), want %s (%d, %d)",
+						tc.param.Precision, res.MapName, res.X, res.Y, tc.mapName, tc.x, tc.y)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestGetMapName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"map01_lv001.png", "map01_lv001"},
+		{"map01_lv001_merged.png", "map01_lv001"},
+		{"map01_lv001_tier_19_merged.webp", "map01_lv001_tier_19"},
+		{"map02_lv003.JPG", "map02_lv003"},
+		{"map_merged_lv001.png", "map_merged_lv001"},
+		{"map01.v2_merged.jpeg", "map01.v2"},
+	}
+	for _, tt := range tests {
+		if got := getMapName(tt.filename); got != tt.want {
+			t.Errorf("getMapName(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestIsMapImageFile(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{"a.png", true},
+		{"a.PNG", true},
+		{"a.jpg", true},
+		{"a.Jpeg", true},
+		{"a.webp", true},
+		{"a.gif", false},
+		{"a.png.bak", false},
+		{"map_rect.json", false},
+		{"png", false},
+	}
+	for _, tt := range tests {
+		if got := isMapImageFile(tt.filename); got != tt.want {
+			t.Errorf("isMapImageFile(%q) = %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestListMapFiles(t *testing.T) {
+	mapDir := t.TempDir()
+	for _, name := range []string{"b.png", "a_merged.webp", "c.JPG", "map_rect.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(mapDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(mapDir, "d.png"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := listMapFiles(mapDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a_merged.webp", "b.png", "c.JPG"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("listMapFiles = %v, want %v", got, want)
+	}
+
+	if _, err := listMapFiles(filepath.Join(mapDir, "missing")); err == nil {
+		t.Error("listMapFiles on a missing directory returned no error")
+	}
+}
+
+func TestInitMapsRetriesAfterFailure(t *testing.T) {
+	mapDir := setupTestResource(t)
+	infer := &Infer{}
+
+	if err := infer.initMaps(); err == nil {
+		t.Fatal("initMaps succeeded without any map")
+	}
+
+	writeTestImage(t, filepath.Join(mapDir, "m.png"), newTestMap(40, 30, 9))
+	if err := infer.initMaps(); err != nil {
+		t.Fatalf("initMaps did not retry: %v", err)
+	}
+	if len(infer.maps) != 1 {
+		t.Errorf("loaded %d maps, want 1", len(infer.maps))
+	}
+}
+
+func TestInferImageLocatesMap(t *testing.T) {
+	mapDir := setupTestResource(t)
+	sources := map[string]*image.RGBA{
+		"map01_lv001_merged.png":         newTestMap(420, 320, 21),
+		"map01_lv002.jpg":                newTestMap(380, 300, 22),
+		"map02_lv001.webp":               nil, // Too small to match, must not be chosen
+		"map01_lv001_tier_3_merged.png":  newTestMap(160, 140, 23),
+		"map03_lv001_with_rect_crop.png": newTestMap(500, 400, 24),
+	}
+	for filename, img := range sources {
+		writeTestImage(t, filepath.Join(mapDir, filename), img)
+	}
+	rects := `{"map03_lv001_with_rect_crop": [100, 60, 420, 360]}`
+	if err := os.WriteFile(filepath.Join(mapDir, "map_rect.json"), []byte(rects), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestImage(t, filepath.Join(filepath.Dir(mapDir), filepath.Base(POINTER_PATH)), newTestMap(15, 20, 25))
+
+	tests := []struct {
+		name   string
+		source string
+		regex  string
+		x, y   int
+		want   string
+	}{
+		{"center", "map01_lv001_merged.png", "", 210, 160, "map01_lv001"},
+		{"near top-left", "map01_lv001_merged.png", "", LOC_RADIUS, LOC_RADIUS, "map01_lv001"},
+		{"near bottom-right", "map01_lv002.jpg", "", 380 - LOC_RADIUS - 1, 300 - LOC_RADIUS - 1, "map01_lv002"},
+		{"tier map", "map01_lv001_tier_3_merged.png", "^map01_lv001_tier_3$", 80, 70, "map01_lv001_tier_3"},
+		{"cropped by rect", "map03_lv001_with_rect_crop.png", "^map03_", 250, 200, "map03_lv001_with_rect_crop"},
+	}
+	infer := &Infer{}
+	for _, tt := range tests {
+		for _, precision := range []float64{0.4, 0.6} {
+			t.Run(fmt.Sprintf("%s/precision=%.1f", tt.name, precision), func(t *testing.T) {
+				param := DefaultInferParam()
+				param.Precision = precision
+				if tt.regex != "" {
+					param.MapNameRegex = tt.regex
+				}
+
+				res, err := infer.InferImage(newTestScreen(sources[tt.source], tt.x, tt.y), param)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tol := int(2/precision) + 1
+				if res.MapName != tt.want || abs(res.X-tt.x) > tol || abs(res.Y-tt.y) > tol {
+					t.Errorf("got %s (%d, %d), want %s (%d, %d) within %dpx",
+						res.MapName, res.X, res.Y, tt.want, tt.x, tt.y, tol)
+				}
+				if res.LocConf < param.Threshold {
+					t.Errorf("location confidence %.3f below threshold %.3f", res.LocConf, param.Threshold)
+				}
+			})
+		}
+	}
+}
+
+func TestInferImageGamma(t *testing.T) {
+	maps := setupTestMaps(t)
+	infer := &Infer{}
+	screen := newTestScreen(maps["map01_lv002"], 150, 170)
+	screenCopy := CloneRGBA(screen)
+
+	for _, gamma := range []float64{2.2, 0.45, 1.0} {
+		param := DefaultInferParam()
+		param.Precision = 1.0 // Unscaled maps are shared with the loaded ones
+		param.Gamma = gamma
+		res, err := infer.InferImage(screen, param)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.MapName != "map01_lv002" || abs(res.X-150) > 1 || abs(res.Y-170) > 1 {
+			t.Errorf("gamma %.2f: got %s (%d, %d), want map01_lv002 (150, 170)", gamma, res.MapName, res.X, res.Y)
+		}
+		if got := infer.scaledCache[0].Key.Gamma; got != gamma {
+			t.Errorf("gamma %.2f: scaled maps cached for gamma %.2f", gamma, got)
+		}
+	}
+
+	if MeanAbsDiff(screen, screenCopy) != 0 {
+		t.Error("screen image was modified")
+	}
+	for _, m := range infer.maps {
+		if MeanAbsDiff(m.Img, maps[m.Name]) != 0 {
+			t.Errorf("loaded map %s was modified", m.Name)
+		}
+	}
+
+	param := DefaultInferParam()
+	param.Gamma = 0
+	if _, err := infer.InferImage(screen, param); err == nil {
+		t.Error("gamma 0 was accepted")
+	}
+}
+
+func TestInferImageRejectsInvalidCoarseSteps(t *testing.T) {
+	maps := setupTestMaps(t)
+	screen := newTestScreen(maps["map01_lv001"], 200, 150)
+
+	for _, r := range [][2]int{{0, 3}, {3, 2}} {
+		param := DefaultInferParam()
+		param.CoarseStepMin, param.CoarseStepMax = r[0], r[1]
+		if _, err := (&Infer{}).InferImage(screen, param); err == nil {
+			t.Errorf("coarse step range %v was accepted", r)
+		}
+	}
+
+	param := DefaultInferParam()
+	param.CoarseStepMin, param.CoarseStepMax = 2, 6
+	res, err := (&Infer{}).InferImage(screen, param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MapName != "map01_lv001" || abs(res.X-200) > 6 || abs(res.Y-150) > 6 {
+		t.Errorf("got %s (%d, %d), want map01_lv001 (200, 150)", res.MapName, res.X, res.Y)
+	}
+}
+
+func TestInferImageMatchWorkers(t *testing.T) {
+	maps := setupTestMaps(t)
+	screen := newTestScreen(maps["map01_lv001"], 200, 150)
+
+	param := DefaultInferParam()
+	param.MatchWorkers = -1
+	if _, err := (&Infer{}).InferImage(screen, param); err == nil {
+		t.Error("negative match_workers was accepted")
+	}
+
+	param.MatchWorkers = 1
+	res, err := (&Infer{}).InferImage(screen, param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MapName != "map01_lv001" || abs(res.X-200) > 3 || abs(res.Y-150) > 3 {
+		t.Errorf("got %s (%d, %d), want map01_lv001 (200, 150)", res.MapName, res.X, res.Y)
+	}
+}
+
+func TestWarmupScalesDefaultPrecisions(t *testing.T) {
+	setupTestMaps(t)
+	infer := &Infer{}
+	if err := infer.warmup(); err != nil {
+		t.Fatal(err)
+	}
+	if len(infer.scaledCache) != 2 {
+		t.Fatalf("got %d scaled map sets, want 2", len(infer.scaledCache))
+	}
+	warmed := make([]*scaledMap, 0, 2)
+	for _, entry := range infer.scaledCache {
+		warmed = append(warmed, &entry.Maps[0])
+	}
+
+	// Both the MapTrackerInfer and MapTrackerMove defaults reuse the warmed maps
+	for _, precision := range []float64{DefaultInferParam().Precision, MOVE_INFER_PRECISION} {
+		param := DefaultInferParam()
+		param.Precision = precision
+		if _, err := infer.InferImage(newTestScreen(infer.maps[0].Img, 150, 120), param); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(infer.scaledCache) != 2 {
+		t.Fatalf("got %d scaled map sets after inference, want 2", len(infer.scaledCache))
+	}
+	for _, entry := range infer.scaledCache {
+		if p := &entry.Maps[0]; p != warmed[0] && p != warmed[1] {
+			t.Errorf("scaled maps for %+v were recomputed", entry.Key)
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"image"
 	"math"
 	"regexp"
 	"time"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/maautil"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -16,8 +18,11 @@ import (
 type MapTrackerMove struct{}
 
 type MoveParam struct {
-	MapName string   `json:"map_name"`
-	Targets [][2]int `json:"targets"`
+	MapName             string   `json:"map_name"`
+	Targets             [][2]int `json:"targets"`
+	CaptureTries        int      `json:"capture_tries"`          // Max screen capture attempts per inference
+	CaptureRetryDelayMs int      `json:"capture_retry_delay_ms"` // Initial delay between capture attempts, doubled each retry
+	MatchWorkers        int      `json:"match_workers"`          // Number of coarse search workers, 0 for runtime.NumCPU()
 }
 
 //go:embed messages/emergency_stop.html
@@ -44,10 +49,17 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		log.Error().Msg("No targets provided")
 		return false
 	}
+	if param.CaptureTries <= 0 {
+		param.CaptureTries = CAPTURE_TRIES
+	}
+	if param.CaptureRetryDelayMs <= 0 {
+		param.CaptureRetryDelayMs = CAPTURE_RETRY_DELAY_MS
+	}
 
 	// Prepare variables
 	ctrl := ctx.GetTasker().GetController()
 	aw := NewActionWrapper(ctx, ctrl)
+	defer aw.WaitUI() // ctx should not be used after Run returns
 	inferIntervalDuration := time.Duration(INFER_INTERVAL_MS) * time.Millisecond
 
 	log.Info().Str("map", param.MapName).Int("targets_count", len(param.Targets)).Msg("Starting navigation to targets")
@@ -60,7 +72,7 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		// Show navigation UI
 		if initRes, err := doInfer(ctx, ctrl, param); err == nil && initRes != nil {
 			initDist := math.Hypot(float64(initRes.X-targetX), float64(initRes.Y-targetY))
-			PrintUI(aw.ctx, fmt.Sprintf(navigationMovingHTML, targetX, targetY, int(initDist)))
+			aw.PrintUIAsync(fmt.Sprintf(navigationMovingHTML, targetX, targetY, int(initDist)))
 		} else if err != nil {
 			log.Debug().Err(err).Msg("Initial infer failed for moving UI")
 		}
@@ -176,31 +188,28 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		aw.KeyUpSync(KEY_W, 100)
 	}
 
-	// Show finished UI summary
-	PrintUI(aw.ctx, fmt.Sprintf(navigationFinishedHTML, len(param.Targets)))
+	// Show finished UI summary, once a pending moving message is done
+	aw.WaitUI()
+	aw.PrintUIAsync(fmt.Sprintf(navigationFinishedHTML, len(param.Targets)))
 
 	return true
 }
 
 func doEmergencyStop(aw *ActionWrapper) {
 	log.Warn().Msg("Emergency stop triggered")
-	PrintUI(aw.ctx, emergencyStopHTML)
 	aw.KeyUpSync(KEY_W, 100)
+	aw.WaitUI() // The stop message must not be skipped for a pending one
+	aw.PrintUIAsync(emergencyStopHTML)
 	aw.ctx.GetTasker().PostStop()
 }
 
 func doInfer(ctx *maa.Context, ctrl *maa.Controller, param MoveParam) (*InferResult, error) {
 	// Capture Screen
-	ctrl.PostScreencap().Wait()
-	img, err := ctrl.CacheImage()
+	img, err := captureScreen(ctrl, param.CaptureTries, param.CaptureRetryDelayMs)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get cached image")
+		log.Error().Err(err).Int("tries", param.CaptureTries).Msg("Failed to capture screen")
 		return nil, err
 	}
-	if img == nil {
-		log.Error().Msg("Cached image is nil")
-		return nil, fmt.Errorf("cached image is nil")
-	}
 
 	// Run recognition
 	nodeName := "MapTrackerMove_Infer"
@@ -209,8 +218,9 @@ func doInfer(ctx *maa.Context, ctrl *maa.Controller, param MoveParam) (*InferRes
 			"recognition":        "Custom",
 			"custom_recognition": "MapTrackerInfer",
 			"custom_recognition_param": map[string]any{
-				"precision":      0.6,
+				"precision":      MOVE_INFER_PRECISION,
 				"map_name_regex": "^" + regexp.QuoteMeta(param.MapName) + "$",
+				"match_workers":  param.MatchWorkers,
 			},
 		},
 	}
@@ -227,17 +237,7 @@ func doInfer(ctx *maa.Context, ctrl *maa.Controller, param MoveParam) (*InferRes
 
 	// Extract result
 	var result InferResult
-	var wrapped struct {
-		Best struct {
-			Detail json.RawMessage `json:"detail"`
-		} `json:"best"`
-	}
-
-	if err := json.Unmarshal([]byte(res.DetailJson), &wrapped); err != nil {
-		log.Error().Err(err).Msg("Failed to unmarshal wrapped result")
-		return nil, err
-	}
-	if err := json.Unmarshal(wrapped.Best.Detail, &result); err != nil {
+	if err := maautil.ParseRecognitionDetail(res.DetailJson, &result); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal InferResult")
 		return nil, err
 	}
@@ -249,6 +249,35 @@ func doInfer(ctx *maa.Context, ctrl *maa.Controller, param MoveParam) (*InferRes
 	return &result, nil
 }
 
+// captureScreen captures the screen, retrying with exponential backoff on failure
+func captureScreen(ctrl *maa.Controller, tries int, delayMillis int) (image.Image, error) {
+	var lastErr error
+	delay := time.Duration(delayMillis) * time.Millisecond
+	for t := 0; t < tries; t++ {
+		if t > 0 {
+			log.Debug().Err(lastErr).Int("try", t+1).Dur("delay", delay).Msg("Retrying screen capture")
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		if !ctrl.PostScreencap().Wait().Success() {
+			lastErr = fmt.Errorf("screencap failed")
+			continue
+		}
+		img, err := ctrl.CacheImage()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if img == nil {
+			lastErr = fmt.Errorf("cached image is nil")
+			continue
+		}
+		return img, nil
+	}
+	return nil, lastErr
+}
+
 // calcTargetRotation calculates the angle from (fromX, fromY) to (toX, toY).
 // 0 degrees is North (negative Y), increasing clockwise.
 func calcTargetRotation(fromX, fromY, toX, toY int) int {
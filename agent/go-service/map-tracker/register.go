@@ -3,10 +3,13 @@ package maptracker
 
 import "github.com/MaaXYZ/maa-framework-go/v4"
 
+// inferInstance is the registered MapTrackerInfer, shared with Warmup
+var inferInstance = &Infer{}
+
 // Register registers all custom recognition components for map-tracker package
 func Register() {
 	ensureResourcePathSink()
 
-	maa.AgentServerRegisterCustomRecognition("MapTrackerInfer", &Infer{})
+	maa.AgentServerRegisterCustomRecognition("MapTrackerInfer", inferInstance)
 	maa.AgentServerRegisterCustomAction("MapTrackerMove", &MapTrackerMove{})
 }
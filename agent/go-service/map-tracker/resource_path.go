@@ -37,6 +37,12 @@ func (c *resourcePathSink) OnResourceLoading(resource *maa.Resource, status maa.
 	}
 	resourcePath.Store(abs)
 	log.Debug().Str("resource_path", abs).Msg("Resource loaded; cached path for map-tracker")
+
+	// Preload maps once a bundle providing them is loaded; earlier bundles
+	// (e.g. resource_fast) have no maps and would leave the lookup to fallbacks
+	if _, err := os.Stat(filepath.Join(abs, MAP_DIR)); err == nil {
+		Warmup()
+	}
 }
 
 // getResourceBase returns the cached resource path or common defaults as fallback
@@ -5,9 +5,13 @@ import (
 	"image"
 	"image/draw"
 	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
 	xdraw "golang.org/x/image/draw"
 )
 
@@ -127,6 +131,64 @@ func ToRGBA(img image.Image) *image.RGBA {
 	return dst
 }
 
+// CloneRGBA returns a deep copy of an RGBA image with its origin at (0, 0)
+func CloneRGBA(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		copy(dst.Pix[y*dst.Stride:y*dst.Stride+w*4], src.Pix[y*src.Stride:y*src.Stride+w*4])
+	}
+	return dst
+}
+
+// ApplyGamma maps each RGB channel value v of img in place to 255*(v/255)^gamma
+// using a lookup table. Alpha is left unchanged; gamma 1 is a no-op.
+func ApplyGamma(img *image.RGBA, gamma float64) {
+	if gamma == 1.0 {
+		return
+	}
+	var lut [256]uint8
+	for v := range lut {
+		lut[v] = uint8(math.Round(255.0 * math.Pow(float64(v)/255.0, gamma)))
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < h; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+w*4]
+		for x := 0; x < len(row); x += 4 {
+			row[x] = lut[row[x]]
+			row[x+1] = lut[row[x+1]]
+			row[x+2] = lut[row[x+2]]
+		}
+	}
+}
+
+// MeanAbsDiff returns the mean absolute difference per RGB channel of two images,
+// or +Inf if their sizes differ
+func MeanAbsDiff(a, b *image.RGBA) float64 {
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	if w != b.Rect.Dx() || h != b.Rect.Dy() || w == 0 || h == 0 {
+		return math.Inf(1)
+	}
+	ap, bp, as, bs := a.Pix, b.Pix, a.Stride, b.Stride
+	var sum uint64
+	for y := 0; y < h; y++ {
+		ai, bi := y*as, y*bs
+		for x := 0; x < w; x++ {
+			for c := 0; c < 3; c++ {
+				d := int(ap[ai+c]) - int(bp[bi+c])
+				if d < 0 {
+					d = -d
+				}
+				sum += uint64(d)
+			}
+			ai += 4
+			bi += 4
+		}
+	}
+	return float64(sum) / float64(w*h*3)
+}
+
 func GetNeedleStats(nRGBA *image.RGBA) *NeedleStats {
 	nW, nH := nRGBA.Rect.Dx(), nRGBA.Rect.Dy()
 	var sn, ssn float64
@@ -145,11 +207,29 @@ func GetNeedleStats(nRGBA *image.RGBA) *NeedleStats {
 	return &NeedleStats{Mn: mn, Dn: dn}
 }
 
+// GetCoarseStep returns the coarse search step for a haystack of the given size,
+// clamped to [minStep, maxStep], so that small haystacks are searched finely
+func GetCoarseStep(w, h, minStep, maxStep int) int {
+	step := int(math.Round(math.Sqrt(float64(w*h)) / MATCH_STEP_DIVISOR))
+	return max(minStep, min(maxStep, step))
+}
+
+// getMatchWorkers returns the number of coarse search workers to use,
+// resolving 0 to runtime.NumCPU()
+func getMatchWorkers(workers int) int {
+	if workers > 0 {
+		return workers
+	}
+	return runtime.NumCPU()
+}
+
 func MatchTemplateOptimized(
 	hRGBA *image.RGBA,
 	hInt *IntegralImage,
 	nRGBA *image.RGBA,
 	nStats *NeedleStats,
+	step int,
+	workers int,
 ) (int, int, float64) {
 	hW, hH, nW, nH := hRGBA.Rect.Dx(), hRGBA.Rect.Dy(), nRGBA.Rect.Dx(), nRGBA.Rect.Dy()
 	if nW > hW || nH > hH {
@@ -164,9 +244,11 @@ func MatchTemplateOptimized(
 		x, y int
 		s    float64
 	}
-	numWorkers, step := 6, 3
-	resChan := make(chan result, numWorkers)
+	step = max(1, step)
 	rows := maxY - minY + 1
+	// Never start more workers than there are coarse rows to search
+	numWorkers := min(getMatchWorkers(workers), (rows+step-1)/step)
+	resChan := make(chan result, numWorkers)
 
 	for i := 0; i < numWorkers; i++ {
 		go func(id int) {
@@ -237,11 +319,15 @@ func computeNCCFast(hRGBA *image.RGBA, hInt *IntegralImage, nRGBA *image.RGBA, o
 type ActionWrapper struct {
 	ctx  *maa.Context
 	ctrl *maa.Controller
+
+	// Outstanding message tasks started by PrintUIAsync
+	uiWg   sync.WaitGroup
+	uiBusy atomic.Bool
 }
 
 // NewActionWrapper creates a new ActionWrapper from a context
 func NewActionWrapper(ctx *maa.Context, ctrl *maa.Controller) *ActionWrapper {
-	return &ActionWrapper{ctx, ctrl}
+	return &ActionWrapper{ctx: ctx, ctrl: ctrl}
 }
 
 // ClickSync performs a touch down and up at (x, y)
@@ -291,8 +377,8 @@ func (aw *ActionWrapper) RotateCamera(dx int, durationMillis int, delayMillis in
 
 /* ******** Misc ******** */
 
-// PrintUI displays a message on the UI
-func PrintUI(ctx *maa.Context, content string) bool {
+// PrintUI displays a message on the UI and waits for the message task
+func PrintUI(ctx *maa.Context, content string) {
 	overrideParam := map[string]any{
 		"MapTrackerPrintUI": map[string]any{
 			"pre_delay":  0,
@@ -302,6 +388,51 @@ func PrintUI(ctx *maa.Context, content string) bool {
 			},
 		},
 	}
-	ctx.RunTask("MapTrackerPrintUI", overrideParam)
-	return true
+	if _, err := ctx.RunTask("MapTrackerPrintUI", overrideParam); err != nil {
+		log.Warn().Err(err).Msg("Failed to run PrintUI task")
+	}
+}
+
+// PrintUIAsync displays a message on the UI, waiting at most PRINT_UI_TIMEOUT_MS
+// for the message task so a busy framework never stalls the caller.
+// The message is skipped while an earlier one is still pending.
+// The task keeps using the context, so WaitUI should be called before the action returns.
+func (aw *ActionWrapper) PrintUIAsync(content string) {
+	if !aw.uiBusy.CompareAndSwap(false, true) {
+		log.Debug().Msg("Previous PrintUI task still pending, message skipped")
+		return
+	}
+
+	done := make(chan struct{})
+	aw.uiWg.Add(1)
+	go func() {
+		defer aw.uiWg.Done()
+		defer aw.uiBusy.Store(false)
+		defer close(done)
+		PrintUI(aw.ctx, content)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(PRINT_UI_TIMEOUT_MS * time.Millisecond):
+		log.Warn().Int("timeoutMs", PRINT_UI_TIMEOUT_MS).Msg("PrintUI task timed out, continuing without waiting")
+	}
+}
+
+// WaitUI waits at most PRINT_UI_TIMEOUT_MS for the message tasks started by
+// PrintUIAsync to finish. MaaFramework cannot cancel a running task, so a task
+// still running after the timeout is left behind and keeps using the context
+// until the framework returns from it, at the latest when the tasker is stopped.
+func (aw *ActionWrapper) WaitUI() {
+	done := make(chan struct{})
+	go func() {
+		aw.uiWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(PRINT_UI_TIMEOUT_MS * time.Millisecond):
+		log.Warn().Int("timeoutMs", PRINT_UI_TIMEOUT_MS).Msg("PrintUI task still running, leaving it behind")
+	}
 }
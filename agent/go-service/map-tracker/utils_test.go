@@ -0,0 +1,185 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// newTestMap synthesizes a deterministic map with smooth value noise,
+// so that matching behaves like on real terrain rather than on white noise
+func newTestMap(w, h int, seed int64) *image.RGBA {
+	const cell = 8
+	rng := rand.New(rand.NewSource(seed))
+	gw, gh := w/cell+2, h/cell+2
+	grid := make([][3]float64, gw*gh)
+	for i := range grid {
+		grid[i] = [3]float64{rng.Float64() * 255, rng.Float64() * 255, rng.Float64() * 255}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		gy, fy := y/cell, float64(y%cell)/cell
+		for x := 0; x < w; x++ {
+			gx, fx := x/cell, float64(x%cell)/cell
+			o := y*img.Stride + x*4
+			for c := 0; c < 3; c++ {
+				v00, v10 := grid[gy*gw+gx][c], grid[gy*gw+gx+1][c]
+				v01, v11 := grid[(gy+1)*gw+gx][c], grid[(gy+1)*gw+gx+1][c]
+				top := v00*(1-fx) + v10*fx
+				bottom := v01*(1-fx) + v11*fx
+				img.Pix[o+c] = uint8(top*(1-fy) + bottom*fy)
+			}
+			img.Pix[o+3] = 255
+		}
+	}
+	return img
+}
+
+// cropTestNeedle copies a w*h window at (x, y) of src into a new image
+func cropTestNeedle(src *image.RGBA, x, y, w, h int) *image.RGBA {
+	return CloneRGBA(src.SubImage(image.Rect(x, y, x+w, y+h)).(*image.RGBA))
+}
+
+func TestMatchTemplateOptimized(t *testing.T) {
+	const mapW, mapH, needleSize = 320, 240, 33
+	hay := newTestMap(mapW, mapH, 1)
+	hayInt := NewIntegralImage(hay)
+	maxX, maxY := mapW-needleSize, mapH-needleSize
+
+	positions := []struct {
+		name string
+		x, y int
+	}{
+		{"top-left corner", 0, 0},
+		{"top-right corner", maxX, 0},
+		{"bottom-left corner", 0, maxY},
+		{"bottom-right corner", maxX, maxY},
+		{"top edge", 150, 0},
+		{"left edge", 0, 101},
+		{"center", 143, 97},
+		{"off-grid", 77, 181},
+	}
+	steps := []int{1, 2, 3, 6}
+
+	for _, pos := range positions {
+		needle := cropTestNeedle(hay, pos.x, pos.y, needleSize, needleSize)
+		stats := GetNeedleStats(needle)
+		for _, step := range steps {
+			t.Run(fmt.Sprintf("%s/step=%d", pos.name, step), func(t *testing.T) {
+				x, y, score := MatchTemplateOptimized(hay, hayInt, needle, stats, step, 0)
+				if abs(x-pos.x) > 1 || abs(y-pos.y) > 1 {
+					t.Errorf("got (%d, %d), want (%d, %d) within 1px", x, y, pos.x, pos.y)
+				}
+				if score < 0.99 {
+					t.Errorf("score %.4f, want >= 0.99 for an exact crop", score)
+				}
+			})
+		}
+	}
+}
+
+func TestMatchTemplateOptimizedNeedleLargerThanHaystack(t *testing.T) {
+	hay := newTestMap(20, 20, 2)
+	needle := newTestMap(30, 30, 3)
+	x, y, score := MatchTemplateOptimized(hay, NewIntegralImage(hay), needle, GetNeedleStats(needle), 1, 0)
+	if x != 0 || y != 0 || score != 0.0 {
+		t.Errorf("got (%d, %d, %f), want (0, 0, 0)", x, y, score)
+	}
+}
+
+func TestGetCoarseStep(t *testing.T) {
+	tests := []struct {
+		w, h             int
+		minStep, maxStep int
+		want             int
+	}{
+		{1, 1, 1, 3, 1},
+		{40, 40, 1, 3, 2},
+		{60, 60, 1, 3, 3},
+		{5000, 5000, 1, 3, 3},
+		{5000, 5000, 1, 6, 6},
+		{40, 40, 3, 6, 3},
+		{100, 100, 2, 8, 5},
+	}
+	for _, tt := range tests {
+		if got := GetCoarseStep(tt.w, tt.h, tt.minStep, tt.maxStep); got != tt.want {
+			t.Errorf("GetCoarseStep(%d, %d, %d, %d) = %d, want %d", tt.w, tt.h, tt.minStep, tt.maxStep, got, tt.want)
+		}
+	}
+}
+
+func TestApplyGamma(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range img.Pix {
+		img.Pix[i] = []uint8{0, 128, 255, 77}[i%4]
+	}
+
+	ApplyGamma(img, 1.0)
+	if img.Pix[1] != 128 {
+		t.Fatalf("gamma 1 changed a pixel to %d", img.Pix[1])
+	}
+
+	// Only the sub-image is affected
+	sub := img.SubImage(image.Rect(1, 1, 3, 2)).(*image.RGBA)
+	ApplyGamma(sub, 2.0)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			o := img.PixOffset(x, y)
+			want := []uint8{0, 128, 255, 77}
+			if y == 1 && x >= 1 && x < 3 {
+				want = []uint8{0, 64, 255, 77}
+			}
+			for c := 0; c < 4; c++ {
+				if img.Pix[o+c] != want[c] {
+					t.Errorf("pixel (%d, %d) = %v, want %v", x, y, img.Pix[o:o+4], want)
+					break
+				}
+			}
+		}
+	}
+}
+
+func benchmarkMatchTemplate(b *testing.B, step int) {
+	hay := newTestMap(340, 220, 4)
+	hayInt := NewIntegralImage(hay)
+	needle := cropTestNeedle(hay, 150, 90, 33, 33)
+	stats := GetNeedleStats(needle)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MatchTemplateOptimized(hay, hayInt, needle, stats, step, 0)
+	}
+}
+
+func BenchmarkMatchTemplateFine(b *testing.B) { benchmarkMatchTemplate(b, 1) }
+
+func BenchmarkMatchTemplateStep3(b *testing.B) { benchmarkMatchTemplate(b, 3) }
+
+func BenchmarkMatchTemplateCoarse(b *testing.B) {
+	benchmarkMatchTemplate(b, GetCoarseStep(340, 220, 1, 6))
+}
+
+func BenchmarkMatchTemplateWorkers(b *testing.B) {
+	hay := newTestMap(850, 1040, 5) // Size of the largest bundled base map
+	hayInt := NewIntegralImage(hay)
+	needle := cropTestNeedle(hay, 400, 500, 81, 81)
+	stats := GetNeedleStats(needle)
+	step := GetCoarseStep(850, 1040, MATCH_STEP_MIN, MATCH_STEP_MAX)
+
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MatchTemplateOptimized(hay, hayInt, needle, stats, step, workers)
+			}
+		})
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"path/filepath"
+)
+
+// MapReport describes a map image and whether it is usable for matching
+type MapReport struct {
+	Name   string
+	File   string
+	Width  int
+	Height int
+	Err    error // nil if the map is usable
+}
+
+// ValidateMaps decodes and crops every map image in mapDir the same way
+// MapTrackerInfer does, and reports the maps that cannot be used for matching.
+func ValidateMaps(mapDir string) ([]MapReport, error) {
+	rectList := readMapRects(mapDir)
+	filenames, err := listMapFiles(mapDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no map images found in %s", mapDir)
+	}
+
+	miniMapSize := 2*LOC_RADIUS + 1
+	seen := make(map[string]string)
+	reports := make([]MapReport, 0, len(filenames))
+	for _, filename := range filenames {
+		name := getMapName(filename)
+		report := MapReport{Name: name, File: filename}
+
+		var rect *image.Rectangle
+		if r, ok := rectList[name]; ok && len(r) == 4 {
+			r0 := image.Rect(r[0], r[1], r[2], r[3])
+			rect = &r0
+		}
+
+		img, _, _, err := decodeMapImage(filepath.Join(mapDir, filename), rect)
+		switch {
+		case err != nil:
+			report.Err = err
+		case seen[name] != "":
+			report.Err = fmt.Errorf("duplicate map name, also used by %s", seen[name])
+		default:
+			report.Width, report.Height = img.Rect.Dx(), img.Rect.Dy()
+			if report.Width < miniMapSize || report.Height < miniMapSize {
+				report.Err = fmt.Errorf("smaller than the %dx%d mini-map crop", miniMapSize, miniMapSize)
+			} else if stddev := getPixelStdDev(img); stddev < VALIDATE_MIN_STDDEV {
+				report.Err = fmt.Errorf("nearly uniform (pixel stddev %.2f)", stddev)
+			}
+		}
+		if seen[name] == "" {
+			seen[name] = filename
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// getPixelStdDev returns the standard deviation of all RGB channel values of an image
+func getPixelStdDev(img *image.RGBA) float64 {
+	stats := GetNeedleStats(img)
+	cnt := float64(img.Rect.Dx() * img.Rect.Dy() * 3)
+	return stats.Dn / math.Sqrt(cnt)
+}
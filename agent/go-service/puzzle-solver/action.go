@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/maautil"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -103,26 +104,11 @@ func (a *Action) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 	}
 
 	var boardDesc BoardDesc
-	if err := json.Unmarshal([]byte(recData), &boardDesc); err != nil {
+	if err := maautil.ParseRecognitionDetail(recData, &boardDesc); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal board state")
 		return false
 	}
 
-	// MaaFramework wrapping logic: if HueList is missing, check if it's wrapped in "best.detail"
-	if len(boardDesc.HueList) == 0 {
-		var wrapped struct {
-			Best struct {
-				Detail json.RawMessage `json:"detail"`
-			} `json:"best"`
-		}
-		if err := json.Unmarshal([]byte(recData), &wrapped); err == nil && len(wrapped.Best.Detail) > 0 {
-			if err := json.Unmarshal(wrapped.Best.Detail, &boardDesc); err != nil {
-				log.Error().Err(err).Msg("Failed to unmarshal wrapped board state")
-				return false
-			}
-		}
-	}
-
 	// Solve the puzzle
 	placements, err := Solve(&boardDesc)
 	if err != nil {
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"strings"
+
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/aspectratio"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/creditshopping"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/dailyrewards"
@@ -14,23 +17,77 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func registerAll() {
+// disabledModulesEnv is the environment variable listing comma-separated
+// module names to skip in registerAll, e.g. "maptracker,puzzle"
+const disabledModulesEnv = "MAAEND_DISABLED_MODULES"
+
+// module is a named group of custom components and sinks
+type module struct {
+	name     string
+	register func()
+}
+
+var modules = []module{
 	// Register all custom components from each package
-	realtime.Register()
-	importtask.Register()
-	resell.Register()
-	puzzle.Register()
-	essencefilter.Register()
-	creditshopping.Register()
-	dailyrewards.Register()
-	maptracker.Register()
+	{"realtime", realtime.Register},
+	{"importtask", importtask.Register},
+	{"resell", resell.Register},
+	{"puzzle", puzzle.Register},
+	{"essencefilter", essencefilter.Register},
+	{"creditshopping", creditshopping.Register},
+	{"dailyrewards", dailyrewards.Register},
+	{"maptracker", maptracker.Register},
 
 	// Register aspect ratio checker (uses TaskerSink, not custom action/recognition)
-	aspectratio.Register()
+	{"aspectratio", aspectratio.Register},
 
 	// Register HDR checker (uses TaskerSink, warns if HDR is enabled but doesn't stop task)
-	hdrcheck.Register()
+	{"hdrcheck", hdrcheck.Register},
+}
+
+func registerAll() {
+	disabled := getDisabledModules()
+
+	registered := make([]string, 0, len(modules))
+	for _, m := range modules {
+		if disabled[m.name] {
+			log.Info().
+				Str("module", m.name).
+				Msg("Module disabled, skipping registration")
+			continue
+		}
+		m.register()
+		registered = append(registered, m.name)
+	}
 
 	log.Info().
+		Strs("modules", registered).
 		Msg("All custom components and sinks registered successfully")
 }
+
+// getDisabledModules parses the disabled module names from the environment
+func getDisabledModules() map[string]bool {
+	disabled := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv(disabledModulesEnv), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, m := range modules {
+			if m.name == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			log.Warn().
+				Str("env", disabledModulesEnv).
+				Str("module", name).
+				Msg("Unknown module, ignored")
+			continue
+		}
+		disabled[name] = true
+	}
+	return disabled
+}
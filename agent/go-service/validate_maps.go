@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	maptracker "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker"
+)
+
+// runValidateMaps runs the "validate-maps <dir>" subcommand and returns the exit code
+func runValidateMaps(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go-service validate-maps <dir>")
+		return 2
+	}
+
+	reports, err := maptracker.ValidateMaps(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to validate maps: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, r := range reports {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("FAIL  %-32s %v\n", r.Name, r.Err)
+		} else {
+			fmt.Printf("OK    %-32s %dx%d\n", r.Name, r.Width, r.Height)
+		}
+	}
+	fmt.Printf("%d maps checked, %d unusable\n", len(reports), failed)
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}